@@ -23,7 +23,6 @@ import (
 	appsv1 "k8s.io/api/apps/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/apimachinery/pkg/util/yaml"
 	"k8s.io/client-go/kubernetes"
 )
@@ -75,19 +74,19 @@ func (f *Framework) DeleteDeployment(ctx context.Context, namespace, name string
 }
 
 func (f *Framework) WaitUntilDeploymentGone(ctx context.Context, kubeClient kubernetes.Interface, namespace, name string, timeout time.Duration) error {
-	return wait.Poll(time.Second, timeout, func() (bool, error) {
+	return Poll(ctx, timeout, func(ctx context.Context) (bool, string, error) {
 		_, err := f.KubeClient.
 			AppsV1beta2().Deployments(namespace).
 			Get(ctx, name, metav1.GetOptions{})
 
 		if err != nil {
 			if apierrors.IsNotFound(err) {
-				return true, nil
+				return true, "", nil
 			}
 
-			return false, err
+			return false, "", err
 		}
 
-		return false, nil
+		return false, fmt.Sprintf("deployment %s/%s still exists", namespace, name), nil
 	})
 }
@@ -0,0 +1,115 @@
+// Copyright 2017 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package framework
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/remotecommand"
+
+	"github.com/prometheus-operator/prometheus-operator/pkg/k8sutil"
+)
+
+// ExecInPod runs cmd inside container of pod and waits for it to finish,
+// collecting stdout/stderr in memory. Use ExecStream instead for long-lived
+// or high-volume output.
+func (f *Framework) ExecInPod(ctx context.Context, namespace, pod, container string, cmd []string, stdin io.Reader) (string, string, error) {
+	exec, err := f.newPodExecutor(ctx, namespace, pod, container, cmd, stdin != nil)
+	if err != nil {
+		return "", "", err
+	}
+
+	var stdout, stderr bytes.Buffer
+	err = exec.StreamWithContext(ctx, remotecommand.StreamOptions{
+		Stdin:  stdin,
+		Stdout: &stdout,
+		Stderr: &stderr,
+	})
+
+	return stdout.String(), stderr.String(), err
+}
+
+// ExecStream behaves like ExecInPod but streams stdout/stderr back to the
+// caller as they're produced, instead of buffering the whole command
+// output, which matters for commands that tail logs or stream WAL/TSDB
+// dumps. The caller must close both returned readers.
+func (f *Framework) ExecStream(ctx context.Context, namespace, pod, container string, cmd []string, stdin io.Reader) (stdout, stderr io.ReadCloser, err error) {
+	exec, err := f.newPodExecutor(ctx, namespace, pod, container, cmd, stdin != nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	outReader, outWriter := io.Pipe()
+	errReader, errWriter := io.Pipe()
+
+	go func() {
+		streamErr := exec.StreamWithContext(ctx, remotecommand.StreamOptions{
+			Stdin:  stdin,
+			Stdout: outWriter,
+			Stderr: errWriter,
+		})
+		outWriter.CloseWithError(streamErr)
+		errWriter.CloseWithError(streamErr)
+	}()
+
+	return outReader, errReader, nil
+}
+
+// newPodExecutor verifies container is actually running before building the
+// SPDY executor, so callers get a clear error instead of a confusing
+// "unable to upgrade connection" failure against a not-yet-ready pod.
+func (f *Framework) newPodExecutor(ctx context.Context, namespace, podName, container string, cmd []string, hasStdin bool) (remotecommand.Executor, error) {
+	pod, err := f.KubeClient.CoreV1().Pods(namespace).Get(ctx, podName, metav1.GetOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("failed to get pod %s/%s before exec", namespace, podName))
+	}
+
+	running, err := k8sutil.PodRunningAndReady(*pod)
+	if err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("failed to evaluate readiness of pod %s/%s before exec", namespace, podName))
+	}
+	if !running {
+		return nil, errors.Errorf("cannot exec in pod %s/%s: pod is not running and ready (phase: %s)", namespace, podName, pod.Status.Phase)
+	}
+
+	req := f.KubeClient.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Name(podName).
+		Namespace(namespace).
+		SubResource("exec")
+
+	req.VersionedParams(&corev1.PodExecOptions{
+		Container: container,
+		Command:   cmd,
+		Stdin:     hasStdin,
+		Stdout:    true,
+		Stderr:    true,
+		TTY:       false,
+	}, scheme.ParameterCodec)
+
+	exec, err := remotecommand.NewSPDYExecutor(f.Config, "POST", req.URL())
+	if err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("failed to create SPDY executor for pod %s/%s", namespace, podName))
+	}
+
+	return exec, nil
+}
@@ -24,7 +24,6 @@ import (
 
 	"k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/rest"
 
 	"github.com/pkg/errors"
@@ -48,36 +47,39 @@ func PathToOSFile(relativePath string) (*os.File, error) {
 // WaitForPodsReady waits for a selection of Pods to be running and each
 // container to pass its readiness check.
 func (f *Framework) WaitForPodsReady(ctx context.Context, namespace string, timeout time.Duration, expectedReplicas int, opts metav1.ListOptions) error {
-	return wait.Poll(time.Second, timeout, func() (bool, error) {
+	return Poll(ctx, timeout, func(ctx context.Context) (bool, string, error) {
 		pl, err := f.KubeClient.CoreV1().Pods(namespace).List(ctx, opts)
 		if err != nil {
-			return false, err
+			return false, "", err
 		}
 
 		runningAndReady := 0
+		var notReady []string
 		for _, p := range pl.Items {
 			isRunningAndReady, err := k8sutil.PodRunningAndReady(p)
 			if err != nil {
-				return false, err
+				return false, "", err
 			}
 
 			if isRunningAndReady {
 				runningAndReady++
+			} else {
+				notReady = append(notReady, fmt.Sprintf("%s (phase: %s)", p.Name, p.Status.Phase))
 			}
 		}
 
 		if runningAndReady == expectedReplicas {
-			return true, nil
+			return true, "", nil
 		}
-		return false, nil
+		return false, fmt.Sprintf("%d/%d pods ready, not ready: %v", runningAndReady, expectedReplicas, notReady), nil
 	})
 }
 
 func (f *Framework) WaitForPodsRunImage(ctx context.Context, namespace string, expectedReplicas int, image string, opts metav1.ListOptions) error {
-	return wait.Poll(time.Second, time.Minute*5, func() (bool, error) {
+	return Poll(ctx, time.Minute*5, func(ctx context.Context) (bool, string, error) {
 		pl, err := f.KubeClient.CoreV1().Pods(namespace).List(ctx, opts)
 		if err != nil {
-			return false, err
+			return false, "", err
 		}
 
 		runningImage := 0
@@ -88,27 +90,41 @@ func (f *Framework) WaitForPodsRunImage(ctx context.Context, namespace string, e
 		}
 
 		if runningImage == expectedReplicas {
-			return true, nil
+			return true, "", nil
 		}
-		return false, nil
+		return false, fmt.Sprintf("%d/%d pods running image %s", runningImage, expectedReplicas, image), nil
 	})
 }
 
+// WaitForHTTPSuccessStatusCode keeps its original signature for existing
+// call sites; use WaitForHTTPSuccessStatusCodeWithContext to make the wait
+// cancellable.
 func WaitForHTTPSuccessStatusCode(timeout time.Duration, url string) error {
-	var resp *http.Response
-	err := wait.Poll(time.Second, timeout, func() (bool, error) {
-		var err error
-		resp, err = http.Get(url)
-		if err == nil && resp.StatusCode == 200 {
-			return true, nil
+	return WaitForHTTPSuccessStatusCodeWithContext(context.Background(), timeout, url)
+}
+
+func WaitForHTTPSuccessStatusCodeWithContext(ctx context.Context, timeout time.Duration, url string) error {
+	var lastStatus int
+	err := Poll(ctx, timeout, func(ctx context.Context) (bool, string, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return false, "", err
 		}
-		return false, nil
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return false, "", nil
+		}
+		defer resp.Body.Close()
+
+		lastStatus = resp.StatusCode
+		return resp.StatusCode == http.StatusOK, fmt.Sprintf("last status code was %d", resp.StatusCode), nil
 	})
 
 	return errors.Wrap(err, fmt.Sprintf(
-		"waiting for %v to return a successful status code timed out. Last response from server was: %v",
+		"waiting for %v to return a successful status code timed out, last status code was %d",
 		url,
-		resp,
+		lastStatus,
 	))
 }
 
@@ -0,0 +1,185 @@
+// Copyright 2017 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package framework
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/restmapper"
+)
+
+// fieldManager identifies this test framework's writes for server-side
+// apply, so reapplying the same fixture doesn't fight other field owners.
+const fieldManager = "po-e2e"
+
+// LoadObjects streams a multi-document YAML (or JSON) fixture and decodes
+// each document into an unstructured.Unstructured, so callers can load
+// whole scenarios - operator, RBAC, CRs - without per-kind MakeX helpers.
+func LoadObjects(pathToYaml string) ([]unstructured.Unstructured, error) {
+	manifest, err := PathToOSFile(pathToYaml)
+	if err != nil {
+		return nil, err
+	}
+	defer manifest.Close()
+
+	decoder := yaml.NewYAMLOrJSONDecoder(manifest, 4096)
+
+	var objects []unstructured.Unstructured
+	for {
+		obj := unstructured.Unstructured{}
+		if err := decoder.Decode(&obj); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, errors.Wrap(err, fmt.Sprintf("failed to decode object from file %s", pathToYaml))
+		}
+
+		if len(obj.Object) == 0 {
+			continue
+		}
+
+		objects = append(objects, obj)
+	}
+
+	return objects, nil
+}
+
+// restMapper resolves GroupVersionKinds to the GroupVersionResource and
+// scope needed by the dynamic client, caching discovery results since it's
+// invoked once per object in a fixture. The concrete
+// *restmapper.DeferredDiscoveryRESTMapper return type (rather than the bare
+// meta.RESTMapper interface) lets callers Reset() it to pick up newly
+// registered kinds, e.g. a CRD that wasn't Established yet on the first
+// lookup.
+func (f *Framework) restMapper() (*restmapper.DeferredDiscoveryRESTMapper, error) {
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(f.Config)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create discovery client")
+	}
+
+	return restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(discoveryClient)), nil
+}
+
+// ApplyObjects server-side applies every object in objs, injecting
+// namespace for namespace-scoped resources and threading ownerRefs (when
+// given) onto each object so a cascading delete of the owner cleans up the
+// whole scenario. It returns the NamespacedName of every applied object.
+func (f *Framework) ApplyObjects(ctx context.Context, namespace string, objs []unstructured.Unstructured, ownerRefs ...metav1.OwnerReference) ([]types.NamespacedName, error) {
+	mapper, err := f.restMapper()
+	if err != nil {
+		return nil, err
+	}
+
+	dynamicClient, err := dynamic.NewForConfig(f.Config)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create dynamic client")
+	}
+
+	var applied []types.NamespacedName
+	for i := range objs {
+		obj := objs[i]
+
+		gvk := obj.GroupVersionKind()
+		mapping, err := mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+		if err != nil {
+			return applied, errors.Wrap(err, fmt.Sprintf("failed to map %s %s/%s", gvk, obj.GetNamespace(), obj.GetName()))
+		}
+
+		if mapping.Scope.Name() == meta.RESTScopeNameNamespace && obj.GetNamespace() == "" {
+			obj.SetNamespace(namespace)
+		}
+
+		if len(ownerRefs) > 0 {
+			obj.SetOwnerReferences(append(obj.GetOwnerReferences(), ownerRefs...))
+		}
+
+		data, err := obj.MarshalJSON()
+		if err != nil {
+			return applied, errors.Wrap(err, fmt.Sprintf("failed to marshal %s %s/%s", gvk, obj.GetNamespace(), obj.GetName()))
+		}
+
+		var resourceClient dynamic.ResourceInterface
+		if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+			resourceClient = dynamicClient.Resource(mapping.Resource).Namespace(obj.GetNamespace())
+		} else {
+			resourceClient = dynamicClient.Resource(mapping.Resource)
+		}
+
+		applyForce := true
+		result, err := resourceClient.Patch(ctx, obj.GetName(), types.ApplyPatchType, data, metav1.PatchOptions{
+			FieldManager: fieldManager,
+			Force:        &applyForce,
+		})
+		if err != nil {
+			return applied, errors.Wrap(err, fmt.Sprintf("failed to apply %s %s/%s", gvk, obj.GetNamespace(), obj.GetName()))
+		}
+
+		applied = append(applied, types.NamespacedName{Namespace: result.GetNamespace(), Name: result.GetName()})
+	}
+
+	return applied, nil
+}
+
+// DeleteObjects deletes every object in objs, ignoring NotFound errors so
+// callers can use it unconditionally during test cleanup.
+func (f *Framework) DeleteObjects(ctx context.Context, namespace string, objs []unstructured.Unstructured) error {
+	mapper, err := f.restMapper()
+	if err != nil {
+		return err
+	}
+
+	dynamicClient, err := dynamic.NewForConfig(f.Config)
+	if err != nil {
+		return errors.Wrap(err, "failed to create dynamic client")
+	}
+
+	for _, obj := range objs {
+		gvk := obj.GroupVersionKind()
+		mapping, err := mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+		if err != nil {
+			return errors.Wrap(err, fmt.Sprintf("failed to map %s %s/%s", gvk, obj.GetNamespace(), obj.GetName()))
+		}
+
+		ns := obj.GetNamespace()
+		if mapping.Scope.Name() == meta.RESTScopeNameNamespace && ns == "" {
+			ns = namespace
+		}
+
+		var resourceClient dynamic.ResourceInterface
+		if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+			resourceClient = dynamicClient.Resource(mapping.Resource).Namespace(ns)
+		} else {
+			resourceClient = dynamicClient.Resource(mapping.Resource)
+		}
+
+		if err := resourceClient.Delete(ctx, obj.GetName(), metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+			return errors.Wrap(err, fmt.Sprintf("failed to delete %s %s/%s", gvk, ns, obj.GetName()))
+		}
+	}
+
+	return nil
+}
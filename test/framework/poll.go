@@ -0,0 +1,105 @@
+// Copyright 2017 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package framework
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// PollOptions controls the backoff used by Poll. The zero value is not
+// usable; use DefaultPollOptions or start from it.
+type PollOptions struct {
+	Backoff wait.Backoff
+}
+
+// DefaultPollOptions backs off gently enough to avoid hammering the
+// kube-apiserver in large e2e suites, while still noticing a condition
+// that flips quickly.
+var DefaultPollOptions = PollOptions{
+	Backoff: wait.Backoff{
+		Duration: 500 * time.Millisecond,
+		Factor:   1.5,
+		Jitter:   0.2,
+		Cap:      10 * time.Second,
+	},
+}
+
+// ConditionFunc is polled by Poll. Besides the usual (done, error), it
+// returns a human readable description of the last observed state, which
+// Poll folds into the error it returns on timeout so failures are
+// diagnosable without re-running with extra logging.
+type ConditionFunc func(ctx context.Context) (done bool, state string, err error)
+
+// Poll repeatedly evaluates condition with DefaultPollOptions until it
+// reports done, ctx is canceled, or timeout elapses. Unlike wait.Poll, it
+// honors ctx cancellation and backs off exponentially between attempts
+// instead of polling at a fixed interval.
+func Poll(ctx context.Context, timeout time.Duration, condition ConditionFunc) error {
+	return PollWithOptions(ctx, timeout, DefaultPollOptions, condition)
+}
+
+// PollWithOptions is Poll with caller-supplied backoff, for waiters that
+// need to poll more (or less) aggressively than the default.
+func PollWithOptions(ctx context.Context, timeout time.Duration, opts PollOptions, condition ConditionFunc) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	backoff := opts.Backoff
+	delay := backoff.Duration
+
+	var lastErr error
+	var lastState string
+	var lastLogged string
+
+	for {
+		done, state, err := condition(ctx)
+		lastState = state
+		lastErr = err
+
+		// A transient error (e.g. a flaky 500 from the apiserver) shouldn't
+		// abort the whole wait; keep retrying and only surface it if we
+		// still haven't converged by the time we give up.
+		if err == nil && done {
+			return nil
+		}
+
+		// Log progress as it changes, not on every tick, so a slow rollout
+		// is visible in the test log while it's still in flight rather than
+		// only in the error returned on timeout.
+		if state != "" && state != lastLogged {
+			log.Printf("waiting for condition: %s", state)
+			lastLogged = state
+		}
+
+		select {
+		case <-ctx.Done():
+			if lastErr != nil {
+				return errors.Wrap(lastErr, "timed out waiting for condition")
+			}
+			return errors.Errorf("timed out waiting for condition, last observed state: %s", lastState)
+		case <-time.After(wait.Jitter(delay, backoff.Jitter)):
+		}
+
+		delay = time.Duration(float64(delay) * backoff.Factor)
+		if backoff.Cap > 0 && delay > backoff.Cap {
+			delay = backoff.Cap
+		}
+	}
+}
@@ -0,0 +1,171 @@
+// Copyright 2017 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package framework
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"k8s.io/client-go/tools/portforward"
+	"k8s.io/client-go/transport/spdy"
+)
+
+// PortForward represents a live SPDY port-forward session against a single
+// pod. It must be closed once the caller is done probing the pod.
+type PortForward struct {
+	fw        *portforward.PortForwarder
+	ports     []portforward.ForwardedPort
+	stopCh    chan struct{}
+	closeOnce sync.Once
+}
+
+// PortForwardPod establishes a port-forward to podName over the API server,
+// mirroring what `kubectl port-forward` does, so tests can probe endpoints
+// that aren't exposed via a NodePort or ingress. ports follow the same
+// "local:remote" syntax accepted by client-go's portforward package, e.g.
+// "0:9090" picks a random local port.
+func (f *Framework) PortForwardPod(ctx context.Context, namespace, podName string, ports []string) (*PortForward, error) {
+	transport, upgrader, err := spdy.RoundTripperFor(f.Config)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create SPDY round tripper")
+	}
+
+	req := f.KubeClient.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Namespace(namespace).
+		Name(podName).
+		SubResource("portforward")
+
+	dialer := spdy.NewDialer(upgrader, &http.Client{Transport: transport}, "POST", req.URL())
+
+	stopCh := make(chan struct{})
+	readyCh := make(chan struct{})
+	out, errOut := &bytes.Buffer{}, &bytes.Buffer{}
+
+	fw, err := portforward.New(dialer, ports, stopCh, readyCh, out, errOut)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create port-forwarder")
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- fw.ForwardPorts()
+	}()
+
+	select {
+	case <-readyCh:
+	case err := <-errCh:
+		return nil, errors.Wrap(err, fmt.Sprintf("port-forward to pod %s/%s failed: %s", namespace, podName, errOut.String()))
+	case <-ctx.Done():
+		close(stopCh)
+		return nil, ctx.Err()
+	}
+
+	forwardedPorts, err := fw.GetPorts()
+	if err != nil {
+		close(stopCh)
+		return nil, errors.Wrap(err, "failed to read forwarded ports")
+	}
+
+	return &PortForward{fw: fw, ports: forwardedPorts, stopCh: stopCh}, nil
+}
+
+// LocalPort returns the local port bound to the given remote container port,
+// or 0 if remote wasn't part of the forwarded ports.
+func (pf *PortForward) LocalPort(remote int) int {
+	for _, p := range pf.ports {
+		if int(p.Remote) == remote {
+			return int(p.Local)
+		}
+	}
+	return 0
+}
+
+// Close tears down the port-forward session. It is safe to call more than
+// once.
+func (pf *PortForward) Close() {
+	pf.closeOnce.Do(func() {
+		close(pf.stopCh)
+	})
+}
+
+// WaitForHTTPStatus polls path on the port-forwarded pod until it returns
+// one of the acceptable status codes, or timeout elapses. It dials the
+// first port passed to PortForwardPod; use LocalPort directly if the
+// session forwards more than one port.
+func (f *Framework) WaitForHTTPStatus(ctx context.Context, pf *PortForward, path string, acceptable []int, timeout time.Duration) error {
+	if len(pf.ports) == 0 {
+		return errors.New("port-forward session has no forwarded ports")
+	}
+	localPort := int(pf.ports[0].Local)
+
+	url := "http://127.0.0.1:" + strconv.Itoa(localPort) + path
+
+	var lastStatus int
+	var lastBody string
+	err := Poll(ctx, timeout, func(ctx context.Context) (bool, string, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return false, "", err
+		}
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			lastStatus, lastBody = 0, err.Error()
+			return false, "", nil
+		}
+		defer resp.Body.Close()
+
+		body, _ := ioutil.ReadAll(resp.Body)
+		lastStatus, lastBody = resp.StatusCode, string(body)
+
+		for _, code := range acceptable {
+			if resp.StatusCode == code {
+				return true, "", nil
+			}
+		}
+		return false, fmt.Sprintf("last status %d, body: %s", lastStatus, lastBody), nil
+	})
+
+	return errors.Wrap(err, fmt.Sprintf(
+		"waiting for %s to return one of %v timed out, last status was %d, body: %s",
+		url, acceptable, lastStatus, lastBody,
+	))
+}
+
+// ProxyGetPodService proxies a GET of path through a Service, which is the
+// second supported way of reaching an in-cluster endpoint without a
+// port-forward. Unlike ProxyGetPod, which returns an unsent request, it
+// performs the request and returns the response body.
+func (f *Framework) ProxyGetPodService(ctx context.Context, namespace, serviceName, path string) (string, error) {
+	result, err := f.KubeClient.CoreV1().RESTClient().Get().
+		Namespace(namespace).
+		Resource("services").
+		SubResource("proxy").
+		Name(serviceName).
+		Suffix(path).
+		DoRaw(ctx)
+	if err != nil {
+		return "", errors.Wrap(err, fmt.Sprintf("failed to proxy GET %s through service %s/%s", path, namespace, serviceName))
+	}
+	return string(result), nil
+}
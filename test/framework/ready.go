@@ -0,0 +1,332 @@
+// Copyright 2017 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package framework
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/restmapper"
+
+	"github.com/prometheus-operator/prometheus-operator/pkg/k8sutil"
+)
+
+// WaitForResourcesReady waits until every object in objects reports itself
+// ready, according to the per-kind readiness rules below. The rules are
+// chosen by the object's Go type: any object that is not one of the typed
+// API objects handled below is resolved through the dynamic client and is
+// considered ready as soon as it exists, since most CRs don't expose a
+// generic readiness signal. This includes an *unstructured.Unstructured of
+// a handled kind (such as a Deployment loaded with LoadObjects); its
+// kind-specific readiness rule is not applied.
+func (f *Framework) WaitForResourcesReady(ctx context.Context, namespace string, objects []runtime.Object, timeout time.Duration) error {
+	mapper, err := f.restMapper()
+	if err != nil {
+		return err
+	}
+
+	dynamicClient, err := dynamic.NewForConfig(f.Config)
+	if err != nil {
+		return errors.Wrap(err, "failed to create dynamic client for readiness check")
+	}
+
+	return Poll(ctx, timeout, func(ctx context.Context) (bool, string, error) {
+		var notReady []string
+
+		for _, obj := range objects {
+			ready, reason, err := f.isObjectReady(ctx, namespace, obj, mapper, dynamicClient)
+			if err != nil {
+				return false, "", err
+			}
+			if !ready {
+				notReady = append(notReady, reason)
+			}
+		}
+
+		if len(notReady) == 0 {
+			return true, "", nil
+		}
+
+		return false, fmt.Sprintf("resources not ready in namespace %q: %v", namespace, notReady), nil
+	})
+}
+
+// isObjectReady re-fetches obj from the API server and evaluates the
+// readiness rule for its kind, returning a human readable reason when the
+// object isn't ready yet.
+func (f *Framework) isObjectReady(ctx context.Context, namespace string, obj runtime.Object, mapper *restmapper.DeferredDiscoveryRESTMapper, dynamicClient dynamic.Interface) (bool, string, error) {
+	switch o := obj.(type) {
+	case *appsv1.Deployment:
+		d, err := f.KubeClient.AppsV1().Deployments(namespace).Get(ctx, o.Name, metav1.GetOptions{})
+		if err != nil {
+			return false, "", err
+		}
+		return IsDeploymentReady(d)
+	case *appsv1.StatefulSet:
+		s, err := f.KubeClient.AppsV1().StatefulSets(namespace).Get(ctx, o.Name, metav1.GetOptions{})
+		if err != nil {
+			return false, "", err
+		}
+		return IsStatefulSetReady(s)
+	case *appsv1.DaemonSet:
+		d, err := f.KubeClient.AppsV1().DaemonSets(namespace).Get(ctx, o.Name, metav1.GetOptions{})
+		if err != nil {
+			return false, "", err
+		}
+		return IsDaemonSetReady(d)
+	case *appsv1.ReplicaSet:
+		r, err := f.KubeClient.AppsV1().ReplicaSets(namespace).Get(ctx, o.Name, metav1.GetOptions{})
+		if err != nil {
+			return false, "", err
+		}
+		return IsReplicaSetReady(r)
+	case *corev1.Service:
+		s, err := f.KubeClient.CoreV1().Services(namespace).Get(ctx, o.Name, metav1.GetOptions{})
+		if err != nil {
+			return false, "", err
+		}
+		return IsServiceReady(s)
+	case *corev1.PersistentVolumeClaim:
+		p, err := f.KubeClient.CoreV1().PersistentVolumeClaims(namespace).Get(ctx, o.Name, metav1.GetOptions{})
+		if err != nil {
+			return false, "", err
+		}
+		return IsPVCReady(p)
+	case *batchv1.Job:
+		j, err := f.KubeClient.BatchV1().Jobs(namespace).Get(ctx, o.Name, metav1.GetOptions{})
+		if err != nil {
+			return false, "", err
+		}
+		return IsJobReady(j)
+	case *corev1.Pod:
+		p, err := f.KubeClient.CoreV1().Pods(namespace).Get(ctx, o.Name, metav1.GetOptions{})
+		if err != nil {
+			return false, "", err
+		}
+		return IsPodReady(p)
+	case *apiextensionsv1.CustomResourceDefinition:
+		c, err := f.APIExtensionsClient.ApiextensionsV1().CustomResourceDefinitions().Get(ctx, o.Name, metav1.GetOptions{})
+		if err != nil {
+			return false, "", err
+		}
+		return IsCRDReady(c)
+	default:
+		return f.isUnstructuredObjectPresent(ctx, namespace, obj, mapper, dynamicClient)
+	}
+}
+
+// isUnstructuredObjectPresent resolves the object's GVK to a GVR via the
+// REST mapper (backed by real discovery data, unlike a naive pluralization
+// guess) and treats existence as readiness, since there is no generic
+// readiness contract for arbitrary custom resources.
+//
+// mapper is shared across every tick of the enclosing Poll, and its
+// DeferredDiscoveryRESTMapper cache only self-refreshes while it's still
+// missing data; once it has served one successful lookup it considers
+// itself fresh forever. That would permanently wedge a fixture that
+// applies a CRD and an instance of it together, since the CRD often isn't
+// Established yet on the first tick. Reset the cache and retry once on a
+// NoKindMatchError so a CRD that becomes Established mid-wait is picked up.
+func (f *Framework) isUnstructuredObjectPresent(ctx context.Context, namespace string, obj runtime.Object, mapper *restmapper.DeferredDiscoveryRESTMapper, dynamicClient dynamic.Interface) (bool, string, error) {
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return false, "", errors.Wrap(err, "failed to get object accessor for readiness check")
+	}
+
+	gvk := obj.GetObjectKind().GroupVersionKind()
+	mapping, err := mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if meta.IsNoMatchError(err) {
+		mapper.Reset()
+		mapping, err = mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	}
+	if err != nil {
+		return false, "", errors.Wrap(err, fmt.Sprintf("failed to map %s %s/%s", gvk, namespace, accessor.GetName()))
+	}
+
+	ns := namespace
+	if accessor.GetNamespace() != "" {
+		ns = accessor.GetNamespace()
+	}
+
+	var resourceClient dynamic.ResourceInterface
+	if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+		resourceClient = dynamicClient.Resource(mapping.Resource).Namespace(ns)
+	} else {
+		resourceClient = dynamicClient.Resource(mapping.Resource)
+	}
+
+	_, err = resourceClient.Get(ctx, accessor.GetName(), metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, fmt.Sprintf("%s %s/%s: not found", gvk.Kind, ns, accessor.GetName()), nil
+		}
+		return false, "", err
+	}
+
+	return true, "", nil
+}
+
+// IsDeploymentReady reports whether a Deployment has rolled out its latest
+// generation and all replicas are updated and available.
+func IsDeploymentReady(d *appsv1.Deployment) (bool, string, error) {
+	if d.Status.ObservedGeneration < d.Generation {
+		return false, fmt.Sprintf("deployment %s/%s: observedGeneration %d < generation %d", d.Namespace, d.Name, d.Status.ObservedGeneration, d.Generation), nil
+	}
+
+	replicas := int32(1)
+	if d.Spec.Replicas != nil {
+		replicas = *d.Spec.Replicas
+	}
+
+	if d.Status.UpdatedReplicas != replicas || d.Status.AvailableReplicas != replicas {
+		return false, fmt.Sprintf("deployment %s/%s: updated %d/available %d of %d replicas", d.Namespace, d.Name, d.Status.UpdatedReplicas, d.Status.AvailableReplicas, replicas), nil
+	}
+
+	return true, "", nil
+}
+
+// IsStatefulSetReady reports whether a StatefulSet has finished rolling out
+// its current update revision to every replica.
+func IsStatefulSetReady(s *appsv1.StatefulSet) (bool, string, error) {
+	if s.Status.ObservedGeneration < s.Generation {
+		return false, fmt.Sprintf("statefulset %s/%s: observedGeneration %d < generation %d", s.Namespace, s.Name, s.Status.ObservedGeneration, s.Generation), nil
+	}
+
+	if s.Status.CurrentRevision != s.Status.UpdateRevision {
+		return false, fmt.Sprintf("statefulset %s/%s: currentRevision %s != updateRevision %s", s.Namespace, s.Name, s.Status.CurrentRevision, s.Status.UpdateRevision), nil
+	}
+
+	replicas := int32(1)
+	if s.Spec.Replicas != nil {
+		replicas = *s.Spec.Replicas
+	}
+
+	if s.Status.ReadyReplicas != replicas {
+		return false, fmt.Sprintf("statefulset %s/%s: %d/%d replicas ready", s.Namespace, s.Name, s.Status.ReadyReplicas, replicas), nil
+	}
+
+	return true, "", nil
+}
+
+// IsDaemonSetReady reports whether a DaemonSet has scheduled and readied the
+// desired number of pods for its current update.
+func IsDaemonSetReady(d *appsv1.DaemonSet) (bool, string, error) {
+	if d.Status.ObservedGeneration < d.Generation {
+		return false, fmt.Sprintf("daemonset %s/%s: observedGeneration %d < generation %d", d.Namespace, d.Name, d.Status.ObservedGeneration, d.Generation), nil
+	}
+
+	if d.Status.UpdatedNumberScheduled != d.Status.DesiredNumberScheduled || d.Status.NumberReady != d.Status.DesiredNumberScheduled {
+		return false, fmt.Sprintf("daemonset %s/%s: updated %d/ready %d of %d desired", d.Namespace, d.Name, d.Status.UpdatedNumberScheduled, d.Status.NumberReady, d.Status.DesiredNumberScheduled), nil
+	}
+
+	return true, "", nil
+}
+
+// IsReplicaSetReady reports whether a ReplicaSet has its full complement of
+// ready, available replicas for its current generation.
+func IsReplicaSetReady(r *appsv1.ReplicaSet) (bool, string, error) {
+	if r.Status.ObservedGeneration < r.Generation {
+		return false, fmt.Sprintf("replicaset %s/%s: observedGeneration %d < generation %d", r.Namespace, r.Name, r.Status.ObservedGeneration, r.Generation), nil
+	}
+
+	replicas := int32(1)
+	if r.Spec.Replicas != nil {
+		replicas = *r.Spec.Replicas
+	}
+
+	if r.Status.ReadyReplicas != replicas || r.Status.AvailableReplicas != replicas {
+		return false, fmt.Sprintf("replicaset %s/%s: ready %d/available %d of %d replicas", r.Namespace, r.Name, r.Status.ReadyReplicas, r.Status.AvailableReplicas, replicas), nil
+	}
+
+	return true, "", nil
+}
+
+// IsServiceReady reports whether a Service is usable. LoadBalancer Services
+// wait for an ingress address to be assigned; ClusterIP, NodePort and
+// ExternalName Services are ready as soon as they exist.
+func IsServiceReady(s *corev1.Service) (bool, string, error) {
+	if s.Spec.Type != corev1.ServiceTypeLoadBalancer {
+		return true, "", nil
+	}
+
+	if len(s.Status.LoadBalancer.Ingress) == 0 {
+		return false, fmt.Sprintf("service %s/%s: waiting for load balancer ingress", s.Namespace, s.Name), nil
+	}
+
+	return true, "", nil
+}
+
+// IsPVCReady reports whether a PersistentVolumeClaim has been bound to a
+// volume.
+func IsPVCReady(p *corev1.PersistentVolumeClaim) (bool, string, error) {
+	if p.Status.Phase != corev1.ClaimBound {
+		return false, fmt.Sprintf("persistentvolumeclaim %s/%s: phase %s", p.Namespace, p.Name, p.Status.Phase), nil
+	}
+
+	return true, "", nil
+}
+
+// IsJobReady reports whether a Job has reached the Complete condition.
+func IsJobReady(j *batchv1.Job) (bool, string, error) {
+	for _, c := range j.Status.Conditions {
+		if c.Type == batchv1.JobComplete && c.Status == corev1.ConditionTrue {
+			return true, "", nil
+		}
+	}
+
+	return false, fmt.Sprintf("job %s/%s: condition Complete not yet true", j.Namespace, j.Name), nil
+}
+
+// IsPodReady reports whether a Pod is running with all of its containers
+// passing their readiness checks.
+func IsPodReady(p *corev1.Pod) (bool, string, error) {
+	ready, err := k8sutil.PodRunningAndReady(*p)
+	if err != nil || !ready {
+		return false, fmt.Sprintf("pod %s/%s: phase %s", p.Namespace, p.Name, p.Status.Phase), err
+	}
+
+	return true, "", nil
+}
+
+// IsCRDReady reports whether a CustomResourceDefinition has been established
+// and had its names accepted by the API server.
+func IsCRDReady(c *apiextensionsv1.CustomResourceDefinition) (bool, string, error) {
+	established, namesAccepted := false, false
+	for _, cond := range c.Status.Conditions {
+		switch cond.Type {
+		case apiextensionsv1.Established:
+			established = cond.Status == apiextensionsv1.ConditionTrue
+		case apiextensionsv1.NamesAccepted:
+			namesAccepted = cond.Status == apiextensionsv1.ConditionTrue
+		}
+	}
+
+	if !established || !namesAccepted {
+		return false, fmt.Sprintf("customresourcedefinition %s: established=%t namesAccepted=%t", c.Name, established, namesAccepted), nil
+	}
+
+	return true, "", nil
+}